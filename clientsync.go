@@ -8,18 +8,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/echo4eva/necoconeco/internal/api"
+	"github.com/echo4eva/necoconeco/internal/progress"
 	"github.com/echo4eva/necoconeco/internal/utils"
 	"github.com/joho/godotenv"
 	rmq "github.com/rabbitmq/rabbitmq-amqp-go-client/pkg/rabbitmqamqp"
 )
 
+// defaultSyncConcurrency is how many upload/download jobs run at once when
+// SYNC_CONCURRENCY isn't set.
+const defaultSyncConcurrency = 8
+
 var (
 	clientID      string
 	address       string
@@ -28,9 +43,17 @@ var (
 	syncDirectory string
 	apiClient     *api.API
 	fileManager   *utils.FileManager
+	logger        *slog.Logger
 )
 
 func main() {
+	silent := flag.Bool("silent", false, "suppress progress bars and non-essential logging")
+	noProgress := flag.Bool("no-progress", false, "disable progress bars, falling back to plain log lines")
+	restoreSnapshot := flag.String("restore-snapshot", "", "reconstruct the local sync directory from the named snapshot archive before syncing")
+	flag.Parse()
+
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
 	err := godotenv.Load()
 	if err != nil {
 		log.Printf("No environment variables found, %s\n", err)
@@ -44,10 +67,29 @@ func main() {
 	serverURL = os.Getenv("SYNC_SERVER_URL")
 	syncDirectory = os.Getenv("SYNC_DIRECTORY")
 
+	logger = logger.With("client_id", clientID)
+
+	// ctx is cancelled on SIGINT/SIGTERM; it's threaded through processActions
+	// and the API client so an interrupted sync stops in-flight transfers
+	// promptly and persists whatever it hadn't finished yet.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize service objects
 	apiClient = api.NewAPI(serverURL, syncDirectory)
 	fileManager = utils.NewFileManager(syncDirectory)
 
+	// Resume any actions left over from a sync interrupted on a previous run
+	// before doing anything else.
+	if pending, found, err := fileManager.LoadPendingActions(); err != nil {
+		log.Printf("Failed to load pending actions: %s\n", err)
+	} else if found {
+		log.Println("Resuming pending actions from a previous interrupted sync")
+		if report := processActions(ctx, pending, *silent || *noProgress); report.HasErrors() {
+			log.Printf("Resuming pending actions finished with errors: %+v\n", report.Failed)
+		}
+	}
+
 	// Setup RabbitMQ client
 	env := rmq.NewEnvironment(address, nil)
 	defer env.CloseConnections(context.Background())
@@ -89,22 +131,45 @@ func main() {
 	log.Printf("Last snapshot struct: %+v\n", lastSnapshot)
 
 	// Start of sync
-	log.Println("Getting local metadata/current snapshot")
-	currentSnapshot, err := fileManager.GetLocalMetadata()
-	if err != nil {
-		log.Println(err)
-		return
+	var currentSnapshot *utils.DirectoryMetadata
+	if *restoreSnapshot != "" {
+		log.Printf("Restoring local state from snapshot %s\n", *restoreSnapshot)
+		restoredSnapshot, err := fileManager.RestoreSnapshot(*restoreSnapshot)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if err := restoreFiles(ctx, restoredSnapshot); err != nil {
+			log.Println(err)
+			return
+		}
+
+		// Recompute from disk rather than trusting the archive's metadata
+		// directly, so what gets posted to the server always matches the
+		// bytes restoreFiles actually wrote.
+		currentSnapshot, err = fileManager.GetLocalMetadata()
+		if err != nil {
+			log.Println(err)
+			return
+		}
+	} else {
+		log.Println("Getting local metadata/current snapshot")
+		currentSnapshot, err = fileManager.GetLocalMetadata()
+		if err != nil {
+			log.Println(err)
+			return
+		}
 	}
 	log.Printf("Current snapshot struct: %+v\n", currentSnapshot)
 
 	// Check last snapshot existence
 	// --- True: compare last with current, send to server
 	// --- False: send current to server
-	var syncActionMetadata *utils.SyncActionMetadata
+	var response *api.PostSnapshotResponse
 	if exists {
 		log.Println("Last snapshot exists, comparing with current snapshot")
 		finalSnapshot := processSnapshots(lastSnapshot, currentSnapshot)
-		syncActionMetadata, err = postSnapshot(finalSnapshot)
+		response, err = postSnapshot(finalSnapshot)
 		if err != nil {
 			log.Println(err)
 			return
@@ -112,15 +177,79 @@ func main() {
 
 	} else {
 		log.Println("Last snapshot does not exist, sending current snapshot to server")
-		syncActionMetadata, err = postSnapshot(currentSnapshot)
+		response, err = postSnapshot(currentSnapshot)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 	}
 
+	syncActionMetadata := response.SyncActionMetadata
+	if response.ServerSnapshot != nil {
+		markConflicts(lastSnapshot, currentSnapshot, response.ServerSnapshot, syncActionMetadata)
+	}
+
 	log.Println("Processing actions")
-	processActions(syncActionMetadata)
+	report := processActions(ctx, syncActionMetadata, *silent || *noProgress)
+	if len(report.Conflicts) > 0 {
+		log.Printf("Unresolved conflicts: %v\n", report.Conflicts)
+	}
+	log.Printf("Sync report: %d succeeded, %d failed\n", len(report.Succeeded), len(report.Failed))
+	if report.HasErrors() {
+		for path, actionErr := range report.Failed {
+			log.Printf("  %s: %s\n", path, actionErr)
+		}
+		os.Exit(1)
+	}
+}
+
+// restoreFiles reconstructs snapshot's files on disk: each regular file is
+// rewritten from the chunk hashes the snapshot recorded (via
+// apiClient.RestoreFile), each directory is recreated, and any local path
+// snapshot doesn't mention is removed, so the sync directory actually ends
+// up matching that archived version instead of just being treated as if it
+// did.
+func restoreFiles(ctx context.Context, snapshot *utils.DirectoryMetadata) error {
+	current, err := fileManager.GetLocalMetadata()
+	if err != nil {
+		return err
+	}
+
+	for path := range current.Files {
+		if _, ok := snapshot.Files[path]; ok {
+			continue
+		}
+		denormalizedPath := utils.RelToAbsConvert(syncDirectory, path)
+		if err := os.RemoveAll(denormalizedPath); err != nil {
+			return fmt.Errorf("removing %s, not present in snapshot: %w", path, err)
+		}
+	}
+
+	paths := make([]string, 0, len(snapshot.Files))
+	for path := range snapshot.Files {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return pathDepth(paths[i]) < pathDepth(paths[j])
+	})
+
+	for _, path := range paths {
+		fileMetadata := snapshot.Files[path]
+		denormalizedPath := utils.RelToAbsConvert(syncDirectory, path)
+
+		if fileMetadata.IsDir {
+			if err := utils.MkDir(denormalizedPath); err != nil {
+				return fmt.Errorf("recreating directory %s: %w", path, err)
+			}
+			continue
+		}
+
+		if err := apiClient.RestoreFile(ctx, denormalizedPath, fileMetadata.Chunks, nil); err != nil {
+			return fmt.Errorf("restoring %s: %w", path, err)
+		}
+	}
+
+	return nil
 }
 
 func processSnapshots(lastSnapshot, currentSnapshot *utils.DirectoryMetadata) *utils.DirectoryMetadata {
@@ -138,15 +267,44 @@ func processSnapshots(lastSnapshot, currentSnapshot *utils.DirectoryMetadata) *u
 		}
 	}
 
-	// Get all currentSnapshot metadata
+	// Get all currentSnapshot metadata, tagging each with the hash it had
+	// last time client and server agreed, so the server (and our own
+	// post-response conflict check) can tell a one-sided change from both
+	// sides having changed the same file.
 	for path, fileMetadata := range currentSnapshot.Files {
+		if last, ok := lastSnapshot.Files[path]; ok {
+			fileMetadata.LastSyncedHash = last.Hash
+		}
 		finalSnapshot.Files[path] = fileMetadata
 	}
 
 	return &finalSnapshot
 }
 
-func postSnapshot(finalSnapshot *utils.DirectoryMetadata) (*utils.SyncActionMetadata, error) {
+// markConflicts rewrites syncActionMetadata in place, replacing any
+// upload/download decision with ActionConflict when a file's local hash and
+// the server's hash have both diverged from the last synced hash (and from
+// each other) — i.e. both sides independently changed the same file.
+func markConflicts(lastSnapshot, currentSnapshot, serverSnapshot *utils.DirectoryMetadata, syncActionMetadata *utils.SyncActionMetadata) {
+	for path, action := range syncActionMetadata.Files {
+		if action.Action != utils.ActionUpload && action.Action != utils.ActionDownload {
+			continue
+		}
+
+		local, hasLocal := currentSnapshot.Files[path]
+		server, hasServer := serverSnapshot.Files[path]
+		last, hasLast := lastSnapshot.Files[path]
+		if !hasLocal || !hasServer || !hasLast {
+			continue
+		}
+
+		if local.Hash != last.Hash && server.Hash != last.Hash && local.Hash != server.Hash {
+			syncActionMetadata.Files[path] = utils.FileActionMetadata{Action: utils.ActionConflict}
+		}
+	}
+}
+
+func postSnapshot(finalSnapshot *utils.DirectoryMetadata) (*api.PostSnapshotResponse, error) {
 	log.Println("Posting snapshot to server")
 	postURL := fmt.Sprintf("%s/snapshot", serverURL)
 	log.Printf("Final snapshot to be sent to server: %+v\n", finalSnapshot)
@@ -189,61 +347,288 @@ func postSnapshot(finalSnapshot *utils.DirectoryMetadata) (*utils.SyncActionMeta
 		return nil, err
 	}
 
-	return response.SyncActionMetadata, nil
+	return &response, nil
+}
+
+// SyncReport summarizes the outcome of a processActions run so main can
+// decide whether to exit non-zero.
+type SyncReport struct {
+	Succeeded []string
+	Failed    map[string]error
+	// Conflicts lists paths that both the client and server changed since
+	// the last synced version, resolved per CONFLICT_STRATEGY.
+	Conflicts []string
+}
+
+// HasErrors reports whether any action in the report failed.
+func (r *SyncReport) HasErrors() bool {
+	return len(r.Failed) > 0
+}
+
+func syncConcurrency() int {
+	if v := os.Getenv("SYNC_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultSyncConcurrency
 }
 
-func processActions(syncActionMetadata *utils.SyncActionMetadata) {
+// processActions carries out syncActionMetadata's actions: ActionMkdir
+// actions run first, serially, ordered shallowest-path-first so a directory
+// always exists before anything tries to create a child inside it; the
+// remaining upload/download actions then run concurrently across a bounded
+// worker pool (SYNC_CONCURRENCY, default 8).
+//
+// If ctx is cancelled partway through (e.g. SIGINT/SIGTERM), in-flight
+// transfers are cancelled and workers stop picking up new ones; whatever
+// didn't run, or was cut short by the cancellation, is persisted via
+// fileManager.SavePendingActions so the next run resumes it
+// before doing anything else. CreateDirectorySnapshot only runs once every
+// worker has drained, and only if nothing is left pending.
+func processActions(ctx context.Context, syncActionMetadata *utils.SyncActionMetadata, silent bool) *SyncReport {
+	report := &SyncReport{Failed: make(map[string]error)}
+
 	if syncActionMetadata == nil {
 		log.Println("No sync actions to process")
-		return
+		return report
 	}
 
-	// Iterate through all file actions
-	for normalizedPath, fileActionMetadata := range syncActionMetadata.Files {
-		log.Printf("Processing action %s for file: %s", fileActionMetadata.Action, normalizedPath)
+	reporter := progress.NewReporter(len(syncActionMetadata.Files), silent, logger)
+	defer reporter.Close()
 
-		switch fileActionMetadata.Action {
-		case utils.ActionUpload:
-			// Upload file to server using denormalized (absolute) path
-			// The API client will handle path conversion internally
-			denormalizedPath := utils.RelToAbsConvert(syncDirectory, normalizedPath)
+	mkdirPaths, filePaths := partitionActions(syncActionMetadata.Files)
 
-			uploadResponse, err := apiClient.Upload(denormalizedPath, clientID)
-			if err != nil {
-				log.Printf("Failed to upload %s: %s", normalizedPath, err)
-			} else {
-				log.Printf("Successfully uploaded %s, FileURL: %s", normalizedPath, uploadResponse.FileURL)
-			}
+	var mu sync.Mutex
+	remaining := make(map[string]utils.FileActionMetadata, len(syncActionMetadata.Files))
+	for _, path := range filePaths {
+		remaining[path] = syncActionMetadata.Files[path]
+	}
 
-		case utils.ActionDownload:
-			// Download file from server using normalized (relative) path
-			// The API client will handle path conversion internally
-			err := apiClient.Download(normalizedPath)
-			if err != nil {
-				log.Printf("Failed to download %s: %s", normalizedPath, err)
-			} else {
-				log.Printf("Successfully downloaded %s", normalizedPath)
-			}
-		case utils.ActionMkdir:
-			// Create directory locally using denormalized (absolute) path
-			denormalizedPath := utils.RelToAbsConvert(syncDirectory, normalizedPath)
-			err := utils.MkDir(denormalizedPath)
-			if err != nil {
-				log.Printf("Failed to create directory %s: %s", normalizedPath, err)
-			} else {
-				log.Printf("Successfully created directory %s", normalizedPath)
+	// ActionMkdir first, serially, shallowest paths first so parent
+	// directories exist before their children do.
+	for _, normalizedPath := range mkdirPaths {
+		runAction(ctx, report, &mu, normalizedPath, syncActionMetadata.Files[normalizedPath], reporter)
+	}
+
+	// Remaining upload/download actions run across a bounded worker pool.
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < syncConcurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for normalizedPath := range jobs {
+				runAction(ctx, report, &mu, normalizedPath, syncActionMetadata.Files[normalizedPath], reporter)
+				mu.Lock()
+				// A transfer that failed because ctx was cancelled mid-flight
+				// stays in remaining so it's persisted for the next run.
+				if _, failed := report.Failed[normalizedPath]; !failed || ctx.Err() == nil {
+					delete(remaining, normalizedPath)
+				}
+				mu.Unlock()
 			}
-		default:
-			log.Printf("Unknown action: %s for file: %s", fileActionMetadata.Action, normalizedPath)
+		}()
+	}
+
+dispatch:
+	for _, normalizedPath := range filePaths {
+		select {
+		case jobs <- normalizedPath:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	mu.Lock()
+	stillRemaining := make(map[string]utils.FileActionMetadata, len(remaining))
+	for path, action := range remaining {
+		stillRemaining[path] = action
+	}
+	mu.Unlock()
+
+	if len(stillRemaining) > 0 {
+		logger.Warn("sync interrupted, persisting remaining actions for next run", "remaining", len(stillRemaining))
+		if err := fileManager.SavePendingActions(&utils.SyncActionMetadata{Files: stillRemaining}); err != nil {
+			log.Printf("Failed to save pending actions: %s\n", err)
 		}
+		return report
+	}
+
+	if err := fileManager.ClearPendingActions(); err != nil {
+		log.Printf("Failed to clear pending actions: %s\n", err)
 	}
 
-	// After processing all actions, create a new snapshot
+	// After all workers drain, create a new snapshot.
 	log.Println("Creating new snapshot")
-	err := fileManager.CreateDirectorySnapshot()
-	if err != nil {
+	if err := fileManager.CreateDirectorySnapshot(report.Conflicts); err != nil {
 		log.Printf("Failed to create snapshot after sync: %s", err)
 	} else {
 		log.Println("Successfully created snapshot after sync")
 	}
+
+	return report
+}
+
+// partitionActions splits actions into mkdir paths (sorted shallowest-first)
+// and the remaining upload/download paths.
+func partitionActions(files map[string]utils.FileActionMetadata) (mkdirPaths, filePaths []string) {
+	for normalizedPath, action := range files {
+		if action.Action == utils.ActionMkdir {
+			mkdirPaths = append(mkdirPaths, normalizedPath)
+		} else {
+			filePaths = append(filePaths, normalizedPath)
+		}
+	}
+
+	sort.Slice(mkdirPaths, func(i, j int) bool {
+		return pathDepth(mkdirPaths[i]) < pathDepth(mkdirPaths[j])
+	})
+
+	return mkdirPaths, filePaths
+}
+
+func pathDepth(normalizedPath string) int {
+	return strings.Count(normalizedPath, "/")
+}
+
+// runAction performs a single sync action and records its outcome into
+// report (guarded by mu, since it's shared across worker goroutines).
+func runAction(ctx context.Context, report *SyncReport, mu *sync.Mutex, normalizedPath string, fileActionMetadata utils.FileActionMetadata, reporter progress.Reporter) {
+	actionLogger := logger.With("action", fileActionMetadata.Action, "path", normalizedPath)
+
+	var actionErr error
+	switch fileActionMetadata.Action {
+	case utils.ActionUpload:
+		// Upload file to server using denormalized (absolute) path
+		// The API client will handle path conversion internally
+		denormalizedPath := utils.RelToAbsConvert(syncDirectory, normalizedPath)
+
+		size := int64(0)
+		if info, err := os.Stat(denormalizedPath); err == nil {
+			size = info.Size()
+		}
+		tracker := reporter.StartFile(normalizedPath, size)
+
+		uploadResponse, err := apiClient.Upload(ctx, denormalizedPath, clientID, tracker.Add)
+		if err != nil {
+			actionErr = err
+			actionLogger.Error("upload failed", "err", err)
+		} else {
+			actionLogger.Info("upload succeeded", "bytes", size, "file_url", uploadResponse.FileURL)
+		}
+		tracker.Finish()
+
+	case utils.ActionDownload:
+		// Download file from server using normalized (relative) path
+		// The API client will handle path conversion internally
+		tracker := reporter.StartFile(normalizedPath, 0)
+
+		err := apiClient.Download(ctx, normalizedPath, tracker.Add)
+		if err != nil {
+			actionErr = err
+			actionLogger.Error("download failed", "err", err)
+		} else {
+			actionLogger.Info("download succeeded")
+		}
+		tracker.Finish()
+	case utils.ActionMkdir:
+		// Create directory locally using denormalized (absolute) path
+		tracker := reporter.StartFile(normalizedPath, 0)
+		denormalizedPath := utils.RelToAbsConvert(syncDirectory, normalizedPath)
+		err := utils.MkDir(denormalizedPath)
+		if err != nil {
+			actionErr = err
+			actionLogger.Error("mkdir failed", "err", err)
+		} else {
+			actionLogger.Info("mkdir succeeded")
+		}
+		tracker.Finish()
+	case utils.ActionConflict:
+		mu.Lock()
+		report.Conflicts = append(report.Conflicts, normalizedPath)
+		mu.Unlock()
+
+		tracker := reporter.StartFile(normalizedPath, 0)
+		err := resolveConflict(ctx, normalizedPath, tracker.Add)
+		if err != nil {
+			actionErr = err
+			actionLogger.Error("conflict resolution failed", "err", err)
+		} else {
+			actionLogger.Info("conflict resolved", "strategy", conflictStrategy())
+		}
+		tracker.Finish()
+	default:
+		actionLogger.Warn("unknown action")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if actionErr != nil {
+		report.Failed[normalizedPath] = actionErr
+	} else {
+		report.Succeeded = append(report.Succeeded, normalizedPath)
+	}
+}
+
+// defaultConflictStrategy is used when CONFLICT_STRATEGY isn't set. Keeping
+// both copies is the safest default: a user editing notes on multiple
+// machines never silently loses either side.
+const defaultConflictStrategy = "keep-both"
+
+// conflictStrategy reads CONFLICT_STRATEGY ("local-wins", "remote-wins", or
+// "keep-both"), defaulting to defaultConflictStrategy.
+func conflictStrategy() string {
+	if v := os.Getenv("CONFLICT_STRATEGY"); v != "" {
+		return v
+	}
+	return defaultConflictStrategy
+}
+
+// resolveConflict applies CONFLICT_STRATEGY to a file both the client and
+// server changed since their last synced version:
+//   - "local-wins" uploads the local copy, discarding the server's version.
+//   - "remote-wins" downloads the server's copy, discarding the local version.
+//   - "keep-both" (default) downloads the server's copy to a temp file, and
+//     only once that succeeds renames the local copy aside (with a
+//     "<name>.conflict-<clientID>-<timestamp><ext>" suffix) and the temp
+//     file into its place, so neither side is lost and a failed download
+//     never leaves the canonical path deleted.
+func resolveConflict(ctx context.Context, normalizedPath string, onProgress func(int64)) error {
+	denormalizedPath := utils.RelToAbsConvert(syncDirectory, normalizedPath)
+
+	switch conflictStrategy() {
+	case "local-wins":
+		_, err := apiClient.Upload(ctx, denormalizedPath, clientID, onProgress)
+		return err
+	case "remote-wins":
+		return apiClient.Download(ctx, normalizedPath, onProgress)
+	default:
+		tmpPath := denormalizedPath + ".conflict-download-tmp"
+		if err := apiClient.DownloadTo(ctx, normalizedPath, tmpPath, onProgress); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("downloading server copy of %s: %w", normalizedPath, err)
+		}
+
+		conflictPath := conflictRenamedPath(denormalizedPath)
+		if err := os.Rename(denormalizedPath, conflictPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("setting aside local copy of %s: %w", normalizedPath, err)
+		}
+
+		if err := os.Rename(tmpPath, denormalizedPath); err != nil {
+			return fmt.Errorf("placing downloaded copy of %s: %w", normalizedPath, err)
+		}
+		return nil
+	}
+}
+
+// conflictRenamedPath inserts a ".conflict-<clientID>-<timestamp>" tag before
+// path's extension, e.g. "foo.md" -> "foo.conflict-laptop-1690000000.md".
+func conflictRenamedPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	return fmt.Sprintf("%s.conflict-%s-%d%s", base, clientID, time.Now().Unix(), ext)
 }
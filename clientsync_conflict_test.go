@@ -0,0 +1,96 @@
+//go:build clientsync
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/echo4eva/necoconeco/internal/utils"
+)
+
+func TestMarkConflictsFlagsFilesChangedOnBothSides(t *testing.T) {
+	last := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"a.md": {Path: "a.md", Hash: "h0"},
+		"b.md": {Path: "b.md", Hash: "h0"},
+		"c.md": {Path: "c.md", Hash: "h0"},
+	}}
+	current := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"a.md": {Path: "a.md", Hash: "h1"}, // changed locally only
+		"b.md": {Path: "b.md", Hash: "h2"}, // changed on both sides, differently
+		"c.md": {Path: "c.md", Hash: "h0"}, // unchanged locally
+	}}
+	server := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"a.md": {Path: "a.md", Hash: "h0"}, // unchanged on server
+		"b.md": {Path: "b.md", Hash: "h3"}, // changed on server too, differently from local
+		"c.md": {Path: "c.md", Hash: "h4"}, // changed on server only
+	}}
+
+	actions := &utils.SyncActionMetadata{Files: map[string]utils.FileActionMetadata{
+		"a.md": {Action: utils.ActionUpload},
+		"b.md": {Action: utils.ActionUpload},
+		"c.md": {Action: utils.ActionDownload},
+	}}
+
+	markConflicts(last, current, server, actions)
+
+	if actions.Files["a.md"].Action != utils.ActionUpload {
+		t.Errorf("a.md: got %v, want unchanged ActionUpload (only local side changed)", actions.Files["a.md"].Action)
+	}
+	if actions.Files["b.md"].Action != utils.ActionConflict {
+		t.Errorf("b.md: got %v, want ActionConflict (both sides changed, differently)", actions.Files["b.md"].Action)
+	}
+	if actions.Files["c.md"].Action != utils.ActionDownload {
+		t.Errorf("c.md: got %v, want unchanged ActionDownload (only server side changed)", actions.Files["c.md"].Action)
+	}
+}
+
+func TestMarkConflictsIgnoresNonTransferActions(t *testing.T) {
+	last := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"dir": {Path: "dir", Hash: "h0"},
+	}}
+	current := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"dir": {Path: "dir", Hash: "h1"},
+	}}
+	server := &utils.DirectoryMetadata{Files: map[string]utils.FileMetadata{
+		"dir": {Path: "dir", Hash: "h2"},
+	}}
+
+	actions := &utils.SyncActionMetadata{Files: map[string]utils.FileActionMetadata{
+		"dir": {Action: utils.ActionMkdir},
+	}}
+
+	markConflicts(last, current, server, actions)
+
+	if actions.Files["dir"].Action != utils.ActionMkdir {
+		t.Errorf("got %v, want ActionMkdir left untouched", actions.Files["dir"].Action)
+	}
+}
+
+func TestConflictStrategyDefaultsToKeepBoth(t *testing.T) {
+	t.Setenv("CONFLICT_STRATEGY", "")
+	if got := conflictStrategy(); got != defaultConflictStrategy {
+		t.Errorf("got %q, want default %q", got, defaultConflictStrategy)
+	}
+
+	t.Setenv("CONFLICT_STRATEGY", "local-wins")
+	if got := conflictStrategy(); got != "local-wins" {
+		t.Errorf("got %q, want local-wins", got)
+	}
+}
+
+func TestConflictRenamedPathPreservesExtension(t *testing.T) {
+	clientID = "laptop"
+	got := conflictRenamedPath("/sync/notes/a.md")
+
+	if !strings.HasSuffix(got, ".md") {
+		t.Errorf("got %q, want it to still end in .md", got)
+	}
+	if !strings.Contains(filepath.Base(got), ".conflict-laptop-") {
+		t.Errorf("got %q, want its filename to contain .conflict-laptop-<timestamp>", got)
+	}
+	if filepath.Dir(got) != "/sync/notes" {
+		t.Errorf("got dir %q, want /sync/notes unchanged", filepath.Dir(got))
+	}
+}
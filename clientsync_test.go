@@ -0,0 +1,70 @@
+//go:build clientsync
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/echo4eva/necoconeco/internal/utils"
+)
+
+func TestPathDepth(t *testing.T) {
+	cases := map[string]int{
+		"a.md":       0,
+		"notes/a.md": 1,
+		"a/b/c.md":   2,
+	}
+	for path, want := range cases {
+		if got := pathDepth(path); got != want {
+			t.Errorf("pathDepth(%q) = %d, want %d", path, got, want)
+		}
+	}
+}
+
+func TestPartitionActionsOrdersMkdirShallowestFirst(t *testing.T) {
+	files := map[string]utils.FileActionMetadata{
+		"a/b/c":  {Action: utils.ActionMkdir},
+		"a":      {Action: utils.ActionMkdir},
+		"a/b":    {Action: utils.ActionMkdir},
+		"a/f.md": {Action: utils.ActionUpload},
+	}
+
+	mkdirPaths, filePaths := partitionActions(files)
+
+	if len(mkdirPaths) != 3 {
+		t.Fatalf("got %d mkdir paths, want 3", len(mkdirPaths))
+	}
+	want := []string{"a", "a/b", "a/b/c"}
+	for i, path := range want {
+		if mkdirPaths[i] != path {
+			t.Errorf("mkdirPaths[%d] = %q, want %q (full order: %v)", i, mkdirPaths[i], path, mkdirPaths)
+		}
+	}
+
+	if len(filePaths) != 1 || filePaths[0] != "a/f.md" {
+		t.Errorf("got filePaths %v, want [a/f.md]", filePaths)
+	}
+}
+
+func TestSyncConcurrencyDefaultsAndParsesEnv(t *testing.T) {
+	os.Unsetenv("SYNC_CONCURRENCY")
+	if got := syncConcurrency(); got != defaultSyncConcurrency {
+		t.Errorf("got %d with no env set, want default %d", got, defaultSyncConcurrency)
+	}
+
+	t.Setenv("SYNC_CONCURRENCY", "3")
+	if got := syncConcurrency(); got != 3 {
+		t.Errorf("got %d with SYNC_CONCURRENCY=3, want 3", got)
+	}
+
+	t.Setenv("SYNC_CONCURRENCY", "not-a-number")
+	if got := syncConcurrency(); got != defaultSyncConcurrency {
+		t.Errorf("got %d with invalid SYNC_CONCURRENCY, want default %d", got, defaultSyncConcurrency)
+	}
+
+	t.Setenv("SYNC_CONCURRENCY", "-1")
+	if got := syncConcurrency(); got != defaultSyncConcurrency {
+		t.Errorf("got %d with negative SYNC_CONCURRENCY, want default %d", got, defaultSyncConcurrency)
+	}
+}
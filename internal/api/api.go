@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/echo4eva/necoconeco/internal/utils"
+)
+
+// API is the sync client's handle for file content: it splits local files
+// into content-addressed chunks and streams them to and from Storage.
+// Upload and Download make no requests to the sync server itself.
+type API struct {
+	serverURL     string
+	syncDirectory string
+	httpClient    *http.Client
+	storage       Storage
+}
+
+// localBlobsDirName is where chunks/manifests live when falling back to
+// local disk storage. It's nested under utils.MetadataDirName so it's
+// excluded from FileManager.GetLocalMetadata along with the rest of the
+// client's own bookkeeping, instead of getting re-synced as user files.
+const localBlobsDirName = "blobs"
+
+// NewAPI builds an API client pointed at serverURL, resolving local paths
+// relative to syncDirectory. The file storage backend is selected via the
+// STORAGE_BACKEND env var (e.g. "s3://bucket/prefix", "b2://bucket/prefix");
+// an empty value falls back to local disk under syncDirectory's metadata
+// directory, so the stored chunks/manifests never collide with the files
+// actually being synced.
+func NewAPI(serverURL, syncDirectory string) *API {
+	localBaseDir := filepath.Join(syncDirectory, utils.MetadataDirName, localBlobsDirName)
+
+	storage, err := NewStorage(os.Getenv("STORAGE_BACKEND"), localBaseDir)
+	if err != nil {
+		// Fall back to local disk rather than failing client startup over a
+		// bad/unsupported STORAGE_BACKEND value.
+		log.Printf("Failed to configure STORAGE_BACKEND, falling back to local disk: %s\n", err)
+		storage = NewLocalStorage(localBaseDir)
+	}
+
+	return &API{
+		serverURL:     serverURL,
+		syncDirectory: syncDirectory,
+		httpClient:    http.DefaultClient,
+		storage:       storage,
+	}
+}
+
+// PostSnapshotRequest is the body sent to POST /snapshot.
+type PostSnapshotRequest struct {
+	ClientID      string                   `json:"clientId"`
+	FinalSnapshot *utils.DirectoryMetadata `json:"finalSnapshot"`
+}
+
+// PostSnapshotResponse is the server's reply to POST /snapshot.
+type PostSnapshotResponse struct {
+	SyncActionMetadata *utils.SyncActionMetadata `json:"syncActionMetadata"`
+	// ServerSnapshot is the server's own view of the directory at the time
+	// it computed SyncActionMetadata, used by the client to detect whether
+	// a file it's about to upload/download was also changed server-side
+	// since the last synced version (a conflict).
+	ServerSnapshot *utils.DirectoryMetadata `json:"serverSnapshot"`
+}
+
+// UploadResponse is returned after a successful Upload.
+type UploadResponse struct {
+	FileURL string `json:"fileUrl"`
+}
+
+const chunkKeyPrefix = "chunks/"
+const manifestKeyPrefix = "manifests/"
+
+// Upload splits the file at absolutePath into content-defined,
+// content-addressed chunks and stores only the ones the backend doesn't
+// already have, then commits an ordered manifest so Download can
+// reconstruct the file. clientID is accepted for parity with the server's
+// per-client upload endpoint, which still receives it out-of-band via
+// PostSnapshot.
+//
+// Because already-stored chunks are skipped via Storage.Exists, an upload
+// interrupted partway through simply re-sends its remaining chunks the next
+// time main runs, without re-sending what already made it across.
+//
+// ctx governs the whole transfer: it's checked between chunks so a
+// cancelled sync (e.g. SIGINT) stops promptly instead of finishing whatever
+// file is in flight, and is also passed down into each Storage call so an
+// in-flight network request on a remote backend is cancelled too, not just
+// the gaps between chunks. Each chunk's Put is retried with backoff on
+// transient errors.
+//
+// onProgress, if non-nil, is called after each chunk is accounted for
+// (whether newly stored or already present) with the number of bytes in
+// that chunk, so callers can render real transfer progress instead of a bar
+// that sits at 0% until the whole file is done.
+func (a *API) Upload(ctx context.Context, absolutePath, clientID string, onProgress func(int64)) (*UploadResponse, error) {
+	f, err := os.Open(absolutePath)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", absolutePath, err)
+	}
+	defer f.Close()
+
+	key, err := utils.AbsToRelConvert(a.syncDirectory, absolutePath)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing %s: %w", absolutePath, err)
+	}
+
+	chunks, err := utils.SplitChunks(f)
+	if err != nil {
+		return nil, fmt.Errorf("chunking %s: %w", key, err)
+	}
+
+	manifest := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		manifest[i] = chunk.Hash
+
+		chunkKey := chunkKeyPrefix + chunk.Hash
+		var exists bool
+		err := withRetry(ctx, func() error {
+			var err error
+			exists, err = a.storage.Exists(ctx, chunkKey)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("checking chunk %s: %w", chunk.Hash, err)
+		}
+		if !exists {
+			err = withRetry(ctx, func() error {
+				_, err := a.storage.Put(ctx, chunkKey, bytes.NewReader(chunk.Data))
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("storing chunk %s: %w", chunk.Hash, err)
+			}
+		}
+
+		if onProgress != nil {
+			onProgress(int64(len(chunk.Data)))
+		}
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling manifest for %s: %w", key, err)
+	}
+
+	manifestKey := manifestKeyPrefix + key + ".json"
+	var fileURL string
+	err = withRetry(ctx, func() error {
+		var err error
+		fileURL, err = a.storage.Put(ctx, manifestKey, bytes.NewReader(manifestJSON))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("committing manifest for %s: %w", key, err)
+	}
+
+	return &UploadResponse{FileURL: fileURL}, nil
+}
+
+// Download reads normalizedPath's manifest and streams its chunks, in
+// order, into the client's sync directory. Like Upload, it honors ctx
+// cancellation between chunks, passes ctx down into each Storage call, and
+// retries transient per-chunk errors. onProgress, if non-nil, is called
+// with each chunk's byte count as it's written.
+func (a *API) Download(ctx context.Context, normalizedPath string, onProgress func(int64)) error {
+	destPath := utils.RelToAbsConvert(a.syncDirectory, normalizedPath)
+	return a.DownloadTo(ctx, normalizedPath, destPath, onProgress)
+}
+
+// DownloadTo is Download, but writes to the given absolute destPath instead
+// of normalizedPath's usual location in the sync directory. Callers that
+// need to inspect or set aside an existing local file before accepting the
+// downloaded content (e.g. conflict resolution) can download to a temp path
+// first and only replace the canonical file once the transfer succeeds.
+func (a *API) DownloadTo(ctx context.Context, normalizedPath, destPath string, onProgress func(int64)) error {
+	var manifestJSON []byte
+	err := withRetry(ctx, func() error {
+		manifestReader, err := a.storage.Get(ctx, manifestKeyPrefix+normalizedPath+".json")
+		if err != nil {
+			return err
+		}
+		defer manifestReader.Close()
+
+		manifestJSON, err = io.ReadAll(manifestReader)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fetching manifest for %s: %w", normalizedPath, err)
+	}
+
+	var manifest []string
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return fmt.Errorf("parsing manifest for %s: %w", normalizedPath, err)
+	}
+
+	return a.writeChunks(ctx, destPath, manifest, onProgress)
+}
+
+// RestoreFile writes destPath by streaming the chunks named by chunkHashes,
+// in order, without first fetching a manifest. It's meant for replaying an
+// already-loaded DirectoryMetadata (e.g. an older snapshot archive): the
+// server's current manifest for a path may have moved on since that
+// snapshot was taken, but chunks are content-addressed and permanent, so
+// the exact version the snapshot recorded is still reconstructible from its
+// own FileMetadata.Chunks list.
+func (a *API) RestoreFile(ctx context.Context, destPath string, chunkHashes []string, onProgress func(int64)) error {
+	return a.writeChunks(ctx, destPath, chunkHashes, onProgress)
+}
+
+// writeChunks streams chunkHashes, in order, into destPath, calling
+// onProgress (if non-nil) with each chunk's byte count as it's written.
+func (a *API) writeChunks(ctx context.Context, destPath string, chunkHashes []string, onProgress func(int64)) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return err
+	}
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	for _, chunkHash := range chunkHashes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var n int64
+		err := withRetry(ctx, func() error {
+			var err error
+			n, err = a.copyChunk(ctx, dest, chunkHash)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("downloading chunk %s for %s: %w", chunkHash, destPath, err)
+		}
+
+		if onProgress != nil {
+			onProgress(n)
+		}
+	}
+
+	return nil
+}
+
+func (a *API) copyChunk(ctx context.Context, dest io.Writer, chunkHash string) (int64, error) {
+	r, err := a.storage.Get(ctx, chunkKeyPrefix+chunkHash)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	return io.Copy(dest, r)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+const maxRetryAttempts = 4
+const retryBaseDelay = 250 * time.Millisecond
+
+// withRetry runs fn up to maxRetryAttempts times, retrying with exponential
+// backoff on any error fn returns (it does not tell transient errors apart
+// from permanent ones), and gives up early if ctx is cancelled (e.g. by a
+// SIGINT/SIGTERM).
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	delay := retryBaseDelay
+
+	for attempt := 0; attempt < maxRetryAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt == maxRetryAttempts-1 {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
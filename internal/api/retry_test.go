@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetrySucceedsAfterTransientErrors(t *testing.T) {
+	attempts := 0
+	err := withRetry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("got %d attempts, want 3", attempts)
+	}
+}
+
+func TestWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	wantErr := errors.New("always fails")
+
+	err := withRetry(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+	if attempts != maxRetryAttempts {
+		t.Fatalf("got %d attempts, want %d", attempts, maxRetryAttempts)
+	}
+}
+
+func TestWithRetryStopsOnCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := withRetry(ctx, func() error {
+		attempts++
+		return errors.New("should never run")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	if attempts != 0 {
+		t.Fatalf("got %d attempts against an already-cancelled ctx, want 0", attempts)
+	}
+}
+
+func TestWithRetryStopsWhenContextCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := withRetry(ctx, func() error {
+		attempts++
+		return errors.New("still failing")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got err %v, want context.DeadlineExceeded", err)
+	}
+	if attempts == 0 {
+		t.Fatal("expected at least one attempt before the deadline hit")
+	}
+}
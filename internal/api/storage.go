@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage is a pluggable backend for reading and writing the bytes of
+// synced files. The sync server still owns snapshot/metadata coordination;
+// Storage only moves file content.
+//
+// This repo only contains the client side of that split (this package and
+// clientsync.go); whatever process serves POST /snapshot and the file
+// blobs themselves lives outside it, so it isn't refactored onto this
+// interface here.
+//
+// Every method takes ctx so a cancelled sync (e.g. SIGINT) can interrupt an
+// in-flight network call on backends like S3Storage/B2Storage, not just the
+// gaps between calls.
+type Storage interface {
+	// Put writes the contents of r under key, returning a URL/identifier
+	// for the stored object that can be handed back to the caller. Get
+	// takes the same key, not this returned value.
+	Put(ctx context.Context, key string, r io.Reader) (string, error)
+	// Get opens the content stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether key is already present, so callers can skip
+	// re-uploading content-addressed chunks they've already sent.
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// NewStorage builds a Storage backend from a STORAGE_BACKEND-style URL.
+//
+// Supported schemes:
+//
+//	(empty)    -> local disk, rooted at baseDir
+//	file://... -> local disk, rooted at the URL path
+//	s3://bucket/prefix       -> S3-compatible object storage
+//	b2://bucket/prefix       -> Backblaze B2
+func NewStorage(backend, baseDir string) (Storage, error) {
+	if backend == "" {
+		return NewLocalStorage(baseDir), nil
+	}
+
+	u, err := url.Parse(backend)
+	if err != nil {
+		return nil, fmt.Errorf("parsing STORAGE_BACKEND %q: %w", backend, err)
+	}
+
+	switch u.Scheme {
+	case "", "file":
+		dir := u.Path
+		if dir == "" {
+			dir = baseDir
+		}
+		return NewLocalStorage(dir), nil
+	case "s3":
+		return NewS3Storage(u.Host, strings.TrimPrefix(u.Path, "/"))
+	case "b2":
+		return NewB2Storage(u.Host, strings.TrimPrefix(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_BACKEND scheme %q", u.Scheme)
+	}
+}
+
+// LocalStorage stores files directly on local disk under baseDir. This is the
+// original, pre-Storage-interface behavior.
+type LocalStorage struct {
+	baseDir string
+}
+
+// NewLocalStorage builds a LocalStorage rooted at baseDir.
+func NewLocalStorage(baseDir string) *LocalStorage {
+	return &LocalStorage{baseDir: baseDir}
+}
+
+// ctx is unused here: local disk I/O is synchronous and effectively
+// instantaneous, so there's nothing to cancel mid-call.
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	dest := filepath.Join(s.baseDir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.baseDir, filepath.FromSlash(key)))
+}
+
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(filepath.Join(s.baseDir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/kurin/blazer/b2"
+)
+
+// B2Storage stores files in Backblaze B2, a low-cost alternative to S3 for
+// users who don't want to run a dedicated file-serving node.
+type B2Storage struct {
+	bucketName string
+	prefix     string
+	bucket     *b2.Bucket
+}
+
+// NewB2Storage builds a B2Storage for bucketName, prefixing all keys with
+// prefix. Credentials come from the B2_ACCOUNT_ID and B2_APPLICATION_KEY
+// environment variables.
+func NewB2Storage(bucketName, prefix string) (*B2Storage, error) {
+	ctx := context.Background()
+
+	client, err := b2.NewClient(ctx, os.Getenv("B2_ACCOUNT_ID"), os.Getenv("B2_APPLICATION_KEY"))
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := client.Bucket(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &B2Storage{bucketName: bucketName, prefix: prefix, bucket: bucket}, nil
+}
+
+func (s *B2Storage) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	objectKey := s.objectKey(key)
+
+	w := s.bucket.Object(objectKey).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return "b2://" + s.bucketName + "/" + objectKey, nil
+}
+
+func (s *B2Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.bucket.Object(s.objectKey(key)).NewReader(ctx), nil
+}
+
+func (s *B2Storage) Exists(ctx context.Context, key string) (bool, error) {
+	if _, err := s.bucket.Object(s.objectKey(key)).Attrs(ctx); err != nil {
+		if b2.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *B2Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
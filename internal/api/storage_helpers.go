@@ -0,0 +1,9 @@
+package api
+
+import "bytes"
+
+// newReadSeeker adapts a byte slice to the io.ReadSeeker the AWS SDK
+// requires for PutObject bodies.
+func newReadSeeker(b []byte) *bytes.Reader {
+	return bytes.NewReader(b)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Storage stores files in an S3-compatible object store (AWS S3, MinIO,
+// or any provider speaking the S3 API).
+type S3Storage struct {
+	bucket string
+	prefix string
+	client *s3.Client
+}
+
+// NewS3Storage builds an S3Storage for bucket, prefixing all keys with prefix.
+// Credentials and region are resolved the standard AWS way (env vars,
+// shared config, instance profile); a custom endpoint can be supplied via
+// AWS_ENDPOINT_URL for S3-compatible providers.
+func NewS3Storage(bucket, prefix string) (*S3Storage, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Storage{
+		bucket: bucket,
+		prefix: prefix,
+		client: s3.NewFromConfig(cfg),
+	}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader) (string, error) {
+	objectKey := s.objectKey(key)
+
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+		Body:   newReadSeeker(buf),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return "s3://" + s.bucket + "/" + objectKey, nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *S3Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestLocalStoragePutGetExists(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLocalStorage(t.TempDir())
+
+	exists, err := storage.Exists(ctx, "chunks/abc")
+	if err != nil {
+		t.Fatalf("Exists on missing key: %v", err)
+	}
+	if exists {
+		t.Fatal("Exists reported true for a key never Put")
+	}
+
+	want := []byte("chunk bytes")
+	if _, err := storage.Put(ctx, "chunks/abc", bytes.NewReader(want)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	exists, err = storage.Exists(ctx, "chunks/abc")
+	if err != nil {
+		t.Fatalf("Exists after Put: %v", err)
+	}
+	if !exists {
+		t.Fatal("Exists reported false right after Put")
+	}
+
+	r, err := storage.Get(ctx, "chunks/abc")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading Get result: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLocalStoragePutCreatesNestedDirs(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLocalStorage(t.TempDir())
+
+	if _, err := storage.Put(ctx, "manifests/a/b/c.json", bytes.NewReader([]byte("{}"))); err != nil {
+		t.Fatalf("Put under nested key: %v", err)
+	}
+
+	exists, err := storage.Exists(ctx, "manifests/a/b/c.json")
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if !exists {
+		t.Fatal("Exists reported false for a nested key that was just Put")
+	}
+}
@@ -0,0 +1,134 @@
+// Package progress renders sync progress to the terminal, falling back to
+// plain log lines when stderr isn't a TTY (or progress bars are disabled).
+package progress
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// FileTracker tracks the transfer of a single file. Callers may run
+// multiple trackers concurrently (one per in-flight worker).
+type FileTracker interface {
+	// Add records n more bytes transferred.
+	Add(n int64)
+	// Finish marks the file complete and advances the aggregate bar.
+	Finish()
+}
+
+// Reporter tracks progress across a batch of sync actions.
+type Reporter interface {
+	// StartFile begins tracking a single file of the given size in bytes.
+	// The returned FileTracker is safe to use concurrently with other
+	// files' trackers from the same Reporter.
+	StartFile(path string, size int64) FileTracker
+	// Close finalizes rendering.
+	Close()
+}
+
+// aggregateTemplate is the aggregate bar's template, tracking how many of
+// totalFiles have finished.
+const aggregateTemplate = `{{counters . }} files {{bar . }} {{percent . }} {{etime . }}`
+
+// NewReporter builds a Reporter sized for totalFiles files. It renders live
+// progress bars when stderr is a terminal and silent is false; otherwise it
+// falls back to structured log lines via logger.
+func NewReporter(totalFiles int, silent bool, logger *slog.Logger) Reporter {
+	if silent || !isTerminal(os.Stderr) {
+		return &logReporter{logger: logger}
+	}
+
+	aggregate := pb.New(totalFiles)
+	aggregate.SetTemplateString(aggregateTemplate)
+
+	// StartPool both enters raw terminal mode and registers aggregate,
+	// so every bar added afterwards via pool.Add renders on a shared,
+	// coordinated cursor instead of each bar redrawing its own line
+	// independently (which garbles under concurrent workers).
+	pool, err := pb.StartPool(aggregate)
+	if err != nil {
+		return &logReporter{logger: logger}
+	}
+
+	return &barReporter{pool: pool, aggregate: aggregate}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
+
+// barReporter renders a per-file progress bar for each concurrently
+// transferring file, plus an aggregate bar across all pending actions. All
+// of them render through pool, which serializes their redraws onto a
+// shared cursor so concurrent workers don't garble each other's output.
+type barReporter struct {
+	pool      *pb.Pool
+	aggregate *pb.ProgressBar
+}
+
+func (r *barReporter) StartFile(path string, size int64) FileTracker {
+	// Built via New64 rather than Start64: Start64 would start the bar's
+	// own independent render goroutine immediately, and ProgressBar.Start
+	// is a no-op on a bar that already has one running, so a
+	// pool.Add(alreadyStartedBar) call wouldn't actually bring it under
+	// the pool's coordination. Leaving it unstarted lets pool.Add do that.
+	bar := pb.New64(size)
+	bar.Set(pb.Bytes, true)
+	bar.SetTemplateString(fmt.Sprintf(`%s {{bar . }} {{speed . }} {{etime . }}`, path))
+	r.pool.Add(bar)
+
+	return &barFileTracker{bar: bar, aggregate: r.aggregate}
+}
+
+func (r *barReporter) Close() {
+	r.aggregate.Finish()
+	r.pool.Stop()
+}
+
+type barFileTracker struct {
+	bar       *pb.ProgressBar
+	aggregate *pb.ProgressBar
+}
+
+func (t *barFileTracker) Add(n int64) {
+	t.bar.Add64(n)
+}
+
+func (t *barFileTracker) Finish() {
+	t.bar.Finish()
+	t.aggregate.Increment()
+}
+
+// logReporter is the non-TTY/--silent fallback: plain structured log lines
+// instead of live-updating bars.
+type logReporter struct {
+	logger *slog.Logger
+}
+
+func (r *logReporter) StartFile(path string, size int64) FileTracker {
+	r.logger.Info("transfer started", "path", path, "bytes", size)
+	return &logFileTracker{logger: r.logger, path: path}
+}
+
+func (r *logReporter) Close() {}
+
+type logFileTracker struct {
+	logger *slog.Logger
+	path   string
+	sent   int64
+}
+
+func (t *logFileTracker) Add(n int64) {
+	t.sent += n
+}
+
+func (t *logFileTracker) Finish() {
+	t.logger.Info("transfer finished", "path", t.path, "bytes", t.sent)
+}
@@ -0,0 +1,36 @@
+package progress
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestNewReporterFallsBackToLogReporterWhenSilent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	r := NewReporter(3, true, logger)
+	if _, ok := r.(*logReporter); !ok {
+		t.Fatalf("got %T, want *logReporter when silent is true", r)
+	}
+}
+
+func TestLogFileTrackerAccumulatesBytes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := &logReporter{logger: logger}
+
+	tracker := r.StartFile("notes.md", 100)
+	tracker.Add(40)
+	tracker.Add(60)
+
+	lft, ok := tracker.(*logFileTracker)
+	if !ok {
+		t.Fatalf("got %T, want *logFileTracker", tracker)
+	}
+	if lft.sent != 100 {
+		t.Fatalf("got sent=%d, want 100", lft.sent)
+	}
+
+	// Finish must not panic when logging through a handler that discards its output.
+	tracker.Finish()
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// ChunkSize is the target average chunk size used when splitting files into
+// content-addressed chunks for upload/download.
+const ChunkSize = 4 * 1024 * 1024 // 4MB
+
+// minChunkSize and maxChunkSize bound how far a chunk boundary can drift from
+// ChunkSize: minChunkSize keeps a run of the rolling hash repeatedly hitting
+// the boundary mask from producing a flood of tiny chunks, and maxChunkSize
+// is a hard cutoff so a long stretch that never hits the mask (e.g. a file
+// of all zero bytes) still gets split eventually.
+const (
+	minChunkSize = ChunkSize / 4
+	maxChunkSize = ChunkSize * 4
+)
+
+// chunkMaskBits is chosen so a boundary hits roughly once every ChunkSize
+// bytes on average: 2^22 == 4MB.
+const chunkMaskBits = 22
+const chunkMask = 1<<chunkMaskBits - 1
+
+// gearTable is the fixed mixing table behind the rolling hash SplitChunks
+// uses to find chunk boundaries (Gear hashing, as used by FastCDC). It must
+// stay constant across runs and processes: two clients chunking the same
+// file content need to land on the same boundaries to get any benefit from
+// Storage.Exists dedup, so this is seeded and generated deterministically
+// rather than randomized at startup.
+var gearTable = func() [256]uint64 {
+	var t [256]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range t {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+		t[i] = x
+	}
+	return t
+}()
+
+// Chunk is one content-addressed piece of a file.
+type Chunk struct {
+	Hash string
+	Data []byte
+}
+
+// SplitChunks reads r and splits it into content-addressed, content-defined
+// chunks: boundaries are placed wherever a rolling hash over the bytes seen
+// so far hits a fixed bit pattern, rather than at fixed byte offsets. This
+// means an edit (insertion or deletion) only changes the one or two chunks
+// around it — every chunk before the edit, and every chunk far enough after
+// it that the rolling hash has resynced, hashes identically to before, so
+// Upload's Storage.Exists check skips re-sending them. A purely fixed-offset
+// split would instead reshuffle every chunk after the edit point.
+func SplitChunks(r io.Reader) ([]Chunk, error) {
+	br := bufio.NewReader(r)
+
+	var chunks []Chunk
+	var buf []byte
+	var h uint64
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, b)
+		h = h<<1 + gearTable[b]
+
+		if len(buf) >= maxChunkSize || (len(buf) >= minChunkSize && h&chunkMask == 0) {
+			chunks = append(chunks, Chunk{Hash: HashBytes(buf), Data: buf})
+			buf = nil
+			h = 0
+		}
+	}
+
+	if len(buf) > 0 {
+		chunks = append(chunks, Chunk{Hash: HashBytes(buf), Data: buf})
+	}
+
+	return chunks, nil
+}
+
+// HashBytes returns the hex-encoded SHA-256 digest of data, used as the
+// content-addressed key for a chunk.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
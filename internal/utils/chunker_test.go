@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSplitChunksRespectsMinAndMaxSize(t *testing.T) {
+	data := make([]byte, 10*ChunkSize)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	chunks, err := SplitChunks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitChunks: %v", err)
+	}
+
+	var total int
+	for i, c := range chunks {
+		total += len(c.Data)
+		if len(c.Data) < minChunkSize && i != len(chunks)-1 {
+			t.Errorf("chunk %d has %d bytes, below minChunkSize %d", i, len(c.Data), minChunkSize)
+		}
+		if len(c.Data) > maxChunkSize {
+			t.Errorf("chunk %d has %d bytes, above maxChunkSize %d", i, len(c.Data), maxChunkSize)
+		}
+		if c.Hash != HashBytes(c.Data) {
+			t.Errorf("chunk %d: Hash doesn't match HashBytes(Data)", i)
+		}
+	}
+	if total != len(data) {
+		t.Fatalf("chunks cover %d bytes, want %d", total, len(data))
+	}
+}
+
+func TestSplitChunksIsDeterministic(t *testing.T) {
+	data := make([]byte, 3*ChunkSize)
+	rand.New(rand.NewSource(2)).Read(data)
+
+	first, err := SplitChunks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitChunks: %v", err)
+	}
+	second, err := SplitChunks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitChunks: %v", err)
+	}
+
+	if len(first) != len(second) {
+		t.Fatalf("got %d chunks then %d chunks for identical input", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].Hash != second[i].Hash {
+			t.Errorf("chunk %d: hash differs across identical runs", i)
+		}
+	}
+}
+
+// TestSplitChunksResyncsAfterAnEarlyInsertion is the delta-sync guarantee
+// this chunking scheme exists for: editing near the start of a large file
+// should leave most of its later chunks re-hashing identically, so Upload's
+// Storage.Exists check can skip re-sending them.
+func TestSplitChunksResyncsAfterAnEarlyInsertion(t *testing.T) {
+	data := make([]byte, 8*ChunkSize)
+	rand.New(rand.NewSource(3)).Read(data)
+
+	before, err := SplitChunks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitChunks(before): %v", err)
+	}
+
+	edited := make([]byte, 0, len(data)+16)
+	edited = append(edited, data[:1000]...)
+	edited = append(edited, []byte("a few inserted bytes")...)
+	edited = append(edited, data[1000:]...)
+
+	after, err := SplitChunks(bytes.NewReader(edited))
+	if err != nil {
+		t.Fatalf("SplitChunks(after): %v", err)
+	}
+
+	beforeHashes := make(map[string]bool, len(before))
+	for _, c := range before {
+		beforeHashes[c.Hash] = true
+	}
+
+	reused := 0
+	for _, c := range after {
+		if beforeHashes[c.Hash] {
+			reused++
+		}
+	}
+
+	// A fixed-offset split would reuse none of the chunks after the edit;
+	// content-defined chunking should resync and reuse most of them.
+	if reused < len(before)/2 {
+		t.Fatalf("only reused %d/%d chunks after an early insertion, want most of them", reused, len(before))
+	}
+}
+
+func TestSplitChunksEmptyInput(t *testing.T) {
+	chunks, err := SplitChunks(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("SplitChunks on empty input: %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Fatalf("got %d chunks for empty input, want 0", len(chunks))
+	}
+}
+
+func TestHashBytesDeterministic(t *testing.T) {
+	a := HashBytes([]byte("hello"))
+	b := HashBytes([]byte("hello"))
+	if a != b {
+		t.Fatalf("HashBytes is not deterministic: %q != %q", a, b)
+	}
+
+	if HashBytes([]byte("hello")) == HashBytes([]byte("world")) {
+		t.Fatal("HashBytes produced the same hash for different input")
+	}
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// MetadataDirName is the directory (under syncDirectory) reserved for this
+// client's own bookkeeping — snapshots, pending actions, and (by convention)
+// the local storage backend's blob namespace. It's the one subtree excluded
+// from GetLocalMetadata, so anything synced must live outside it.
+const MetadataDirName = ".metadata"
+
+// FileManager reads the local sync directory and persists directory
+// snapshots used to diff against the server on the next run.
+type FileManager struct {
+	syncDirectory string
+	metadataDir   string
+	retention     int
+	encryptionKey []byte
+}
+
+// NewFileManager builds a FileManager rooted at syncDirectory. Snapshot
+// retention and encryption are configured via SNAPSHOT_RETENTION (number of
+// versioned archives to keep, default 10) and SNAPSHOT_PASSPHRASE (if set,
+// snapshot archives are encrypted at rest with AES-GCM).
+func NewFileManager(syncDirectory string) *FileManager {
+	retention := defaultSnapshotRetention
+	if v := os.Getenv("SNAPSHOT_RETENTION"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			retention = n
+		}
+	}
+
+	var encryptionKey []byte
+	if passphrase := os.Getenv("SNAPSHOT_PASSPHRASE"); passphrase != "" {
+		encryptionKey = deriveSnapshotKey(passphrase)
+	}
+
+	return &FileManager{
+		syncDirectory: syncDirectory,
+		metadataDir:   filepath.Join(syncDirectory, MetadataDirName),
+		retention:     retention,
+		encryptionKey: encryptionKey,
+	}
+}
+
+// GetLocalMetadata walks syncDirectory and builds a DirectoryMetadata
+// reflecting the current on-disk state.
+func (fm *FileManager) GetLocalMetadata() (*DirectoryMetadata, error) {
+	metadata := &DirectoryMetadata{Files: make(map[string]FileMetadata)}
+
+	err := filepath.Walk(fm.syncDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == fm.syncDirectory || filepath.HasPrefix(path, fm.metadataDir) {
+			return nil
+		}
+
+		relPath, err := AbsToRelConvert(fm.syncDirectory, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			metadata.Files[relPath] = FileMetadata{
+				Path:    relPath,
+				IsDir:   true,
+				ModTime: info.ModTime().Unix(),
+			}
+			return nil
+		}
+
+		hash, chunkHashes, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+
+		metadata.Files[relPath] = FileMetadata{
+			Path:    relPath,
+			Hash:    hash,
+			Size:    info.Size(),
+			ModTime: info.ModTime().Unix(),
+			Chunks:  chunkHashes,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// GetLastSnapshot loads the most recently written snapshot archive from
+// disk, if any.
+func (fm *FileManager) GetLastSnapshot() (*DirectoryMetadata, bool, error) {
+	names, err := listSnapshotFiles(fm.metadataDir)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(names) == 0 {
+		return &DirectoryMetadata{Files: make(map[string]FileMetadata)}, false, nil
+	}
+
+	snapshot, err := readSnapshotArchive(filepath.Join(fm.metadataDir, names[len(names)-1]), fm.encryptionKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return snapshot, true, nil
+}
+
+// CreateDirectorySnapshot recomputes local metadata and writes it as a new
+// versioned, zip-compressed (and optionally AES-GCM encrypted) snapshot
+// archive, pruning archives beyond the configured retention. conflictPaths
+// marks which files had a conflict resolved during the sync this snapshot
+// follows, so later tooling can distinguish them from a plain update.
+func (fm *FileManager) CreateDirectorySnapshot(conflictPaths []string) error {
+	snapshot, err := fm.GetLocalMetadata()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range conflictPaths {
+		if fileMetadata, ok := snapshot.Files[path]; ok {
+			fileMetadata.Status = StatusConflict
+			snapshot.Files[path] = fileMetadata
+		}
+	}
+
+	if err := os.MkdirAll(fm.metadataDir, 0o755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(fm.metadataDir, snapshotFileName(time.Now()))
+	if err := writeSnapshotArchive(path, snapshot, fm.encryptionKey); err != nil {
+		return err
+	}
+
+	return pruneSnapshots(fm.metadataDir, fm.retention)
+}
+
+// RestoreSnapshot loads the named snapshot archive (as produced by
+// CreateDirectorySnapshot, e.g. "snapshot-1700000000.zip") and returns the
+// DirectoryMetadata it recorded, including each file's chunk list, which the
+// caller can use to reconstruct that version's actual file content (the
+// archive itself only stores metadata, not file bytes).
+func (fm *FileManager) RestoreSnapshot(name string) (*DirectoryMetadata, error) {
+	names, err := listSnapshotFiles(fm.metadataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range names {
+		if candidate == name {
+			return readSnapshotArchive(filepath.Join(fm.metadataDir, candidate), fm.encryptionKey)
+		}
+	}
+
+	return nil, fmt.Errorf("snapshot %q not found in %s", name, fm.metadataDir)
+}
+
+// hashFile computes the whole-file SHA-256 (used to detect "did this file
+// change at all") alongside the per-chunk SHA-256s (used to upload/download
+// only the chunks that actually changed).
+func hashFile(path string) (string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	tee := io.TeeReader(f, h)
+
+	chunks, err := SplitChunks(tee)
+	if err != nil {
+		return "", nil, err
+	}
+
+	chunkHashes := make([]string, len(chunks))
+	for i, c := range chunks {
+		chunkHashes[i] = c.Hash
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), chunkHashes, nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RelToAbsConvert turns a normalized (relative, forward-slash) path back into
+// an absolute, OS-native path rooted at syncDirectory.
+func RelToAbsConvert(syncDirectory, normalizedPath string) string {
+	return filepath.Join(syncDirectory, filepath.FromSlash(normalizedPath))
+}
+
+// AbsToRelConvert normalizes an absolute, OS-native path into a
+// forward-slash path relative to syncDirectory.
+func AbsToRelConvert(syncDirectory, absolutePath string) (string, error) {
+	rel, err := filepath.Rel(syncDirectory, absolutePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// MkDir creates a directory (and any missing parents) at the given absolute path.
+func MkDir(absolutePath string) error {
+	return os.MkdirAll(absolutePath, 0o755)
+}
+
+// NormalizePath strips trailing slashes and converts OS separators to '/'.
+func NormalizePath(path string) string {
+	return strings.TrimSuffix(filepath.ToSlash(path), "/")
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const pendingActionsFileName = "pending-actions.json"
+
+// SavePendingActions persists the sync actions that hadn't finished yet
+// (e.g. because of a SIGINT mid-sync) so the next run can resume them
+// before computing a new snapshot diff.
+func (fm *FileManager) SavePendingActions(actions *SyncActionMetadata) error {
+	if err := os.MkdirAll(fm.metadataDir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(actions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fm.pendingActionsPath(), data, 0o644)
+}
+
+// LoadPendingActions reads back any actions saved by SavePendingActions, if present.
+func (fm *FileManager) LoadPendingActions() (*SyncActionMetadata, bool, error) {
+	data, err := os.ReadFile(fm.pendingActionsPath())
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var actions SyncActionMetadata
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, false, err
+	}
+
+	return &actions, true, nil
+}
+
+// ClearPendingActions removes the pending-actions file once its actions
+// have all been processed.
+func (fm *FileManager) ClearPendingActions() error {
+	err := os.Remove(fm.pendingActionsPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (fm *FileManager) pendingActionsPath() string {
+	return filepath.Join(fm.metadataDir, pendingActionsFileName)
+}
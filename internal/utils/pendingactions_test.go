@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+func TestPendingActionsRoundTrip(t *testing.T) {
+	fm := NewFileManager(t.TempDir())
+
+	if _, found, err := fm.LoadPendingActions(); err != nil {
+		t.Fatalf("LoadPendingActions before any save: %v", err)
+	} else if found {
+		t.Fatal("LoadPendingActions reported found before anything was saved")
+	}
+
+	want := &SyncActionMetadata{Files: map[string]FileActionMetadata{
+		"a.md": {Action: ActionUpload},
+		"b.md": {Action: ActionDownload},
+	}}
+	if err := fm.SavePendingActions(want); err != nil {
+		t.Fatalf("SavePendingActions: %v", err)
+	}
+
+	got, found, err := fm.LoadPendingActions()
+	if err != nil {
+		t.Fatalf("LoadPendingActions: %v", err)
+	}
+	if !found {
+		t.Fatal("LoadPendingActions reported not found right after SavePendingActions")
+	}
+	if len(got.Files) != 2 || got.Files["a.md"].Action != ActionUpload {
+		t.Fatalf("got %+v, want %+v", got.Files, want.Files)
+	}
+
+	if err := fm.ClearPendingActions(); err != nil {
+		t.Fatalf("ClearPendingActions: %v", err)
+	}
+
+	if _, found, err := fm.LoadPendingActions(); err != nil {
+		t.Fatalf("LoadPendingActions after clear: %v", err)
+	} else if found {
+		t.Fatal("LoadPendingActions still reports found after ClearPendingActions")
+	}
+}
+
+func TestClearPendingActionsWithNoneSavedIsANoOp(t *testing.T) {
+	fm := NewFileManager(t.TempDir())
+	if err := fm.ClearPendingActions(); err != nil {
+		t.Fatalf("ClearPendingActions with nothing saved: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotEntryName = "snapshot.json"
+const snapshotFilePrefix = "snapshot-"
+const snapshotFileExt = ".zip"
+
+const defaultSnapshotRetention = 10
+
+// deriveSnapshotKey turns a user-supplied passphrase into a fixed-size
+// AES-256 key. A plain SHA-256 is sufficient here: the passphrase is
+// expected to come from a secrets-managed env var, not user memory.
+func deriveSnapshotKey(passphrase string) []byte {
+	key := sha256.Sum256([]byte(passphrase))
+	return key[:]
+}
+
+// writeSnapshotArchive zip-compresses snapshot and, if encryptionKey is
+// non-nil, encrypts the archive with AES-GCM before writing it to path.
+func writeSnapshotArchive(path string, snapshot *DirectoryMetadata, encryptionKey []byte) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	entry, err := zw.Create(snapshotEntryName)
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	out := zipBuf.Bytes()
+	if encryptionKey != nil {
+		out, err = encryptBytes(encryptionKey, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, out, 0o600)
+}
+
+// readSnapshotArchive reverses writeSnapshotArchive.
+func readSnapshotArchive(path string, encryptionKey []byte) (*DirectoryMetadata, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if encryptionKey != nil {
+		raw, err = decryptBytes(encryptionKey, raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range zr.File {
+		if f.Name != snapshotEntryName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+
+		var snapshot DirectoryMetadata
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, err
+		}
+		return &snapshot, nil
+	}
+
+	return nil, fmt.Errorf("%s: missing %s entry", path, snapshotEntryName)
+}
+
+func encryptBytes(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decryptBytes(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// snapshotFileName names a versioned snapshot archive after the Unix
+// timestamp it was taken at, so archives sort chronologically by name.
+func snapshotFileName(at time.Time) string {
+	return fmt.Sprintf("%s%d%s", snapshotFilePrefix, at.Unix(), snapshotFileExt)
+}
+
+// listSnapshotFiles returns snapshot archive filenames in metadataDir,
+// oldest first.
+func listSnapshotFiles(metadataDir string) ([]string, error) {
+	entries, err := os.ReadDir(metadataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, snapshotFilePrefix) && strings.HasSuffix(name, snapshotFileExt) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// pruneSnapshots deletes all but the most recent `retention` snapshot
+// archives in metadataDir.
+func pruneSnapshots(metadataDir string, retention int) error {
+	names, err := listSnapshotFiles(metadataDir)
+	if err != nil {
+		return err
+	}
+
+	if retention <= 0 || len(names) <= retention {
+		return nil
+	}
+
+	for _, name := range names[:len(names)-retention] {
+		if err := os.Remove(filepath.Join(metadataDir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot-1.zip")
+
+	want := &DirectoryMetadata{Files: map[string]FileMetadata{
+		"notes/a.md": {Path: "notes/a.md", Hash: "abc", Size: 42, Chunks: []string{"c1", "c2"}},
+	}}
+
+	if err := writeSnapshotArchive(path, want, nil); err != nil {
+		t.Fatalf("writeSnapshotArchive: %v", err)
+	}
+
+	got, err := readSnapshotArchive(path, nil)
+	if err != nil {
+		t.Fatalf("readSnapshotArchive: %v", err)
+	}
+
+	file, ok := got.Files["notes/a.md"]
+	if !ok {
+		t.Fatal("round-tripped snapshot is missing notes/a.md")
+	}
+	if file.Hash != "abc" || file.Size != 42 || len(file.Chunks) != 2 {
+		t.Fatalf("got %+v, want Hash=abc Size=42 len(Chunks)=2", file)
+	}
+}
+
+func TestSnapshotArchiveRoundTripEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot-1.zip")
+	key := deriveSnapshotKey("correct horse battery staple")
+
+	want := &DirectoryMetadata{Files: map[string]FileMetadata{
+		"secret.md": {Path: "secret.md", Hash: "xyz"},
+	}}
+
+	if err := writeSnapshotArchive(path, want, key); err != nil {
+		t.Fatalf("writeSnapshotArchive: %v", err)
+	}
+
+	// Reading with the wrong key must fail rather than silently returning
+	// garbage metadata.
+	wrongKey := deriveSnapshotKey("wrong passphrase")
+	if _, err := readSnapshotArchive(path, wrongKey); err == nil {
+		t.Fatal("readSnapshotArchive succeeded with the wrong encryption key")
+	}
+
+	got, err := readSnapshotArchive(path, key)
+	if err != nil {
+		t.Fatalf("readSnapshotArchive with correct key: %v", err)
+	}
+	if got.Files["secret.md"].Hash != "xyz" {
+		t.Fatalf("got %+v, want Hash=xyz", got.Files["secret.md"])
+	}
+}
+
+func TestPruneSnapshotsKeepsOnlyRetentionMostRecent(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := &DirectoryMetadata{Files: map[string]FileMetadata{}}
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		name := snapshotFileName(base.Add(time.Duration(i) * time.Hour))
+		if err := writeSnapshotArchive(filepath.Join(dir, name), snapshot, nil); err != nil {
+			t.Fatalf("writeSnapshotArchive %d: %v", i, err)
+		}
+	}
+
+	if err := pruneSnapshots(dir, 2); err != nil {
+		t.Fatalf("pruneSnapshots: %v", err)
+	}
+
+	names, err := listSnapshotFiles(dir)
+	if err != nil {
+		t.Fatalf("listSnapshotFiles: %v", err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("got %d snapshots after pruning to 2, want 2: %v", len(names), names)
+	}
+
+	wantLast := snapshotFileName(base.Add(4 * time.Hour))
+	if names[len(names)-1] != wantLast {
+		t.Fatalf("newest remaining snapshot is %q, want %q", names[len(names)-1], wantLast)
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+// ActionType describes what a client should do with a given file during sync.
+type ActionType string
+
+const (
+	ActionUpload   ActionType = "upload"
+	ActionDownload ActionType = "download"
+	ActionMkdir    ActionType = "mkdir"
+	// ActionConflict marks a file that both the client and server changed
+	// since the last synced version, so it needs CONFLICT_STRATEGY
+	// resolution instead of a plain upload/download.
+	ActionConflict ActionType = "conflict"
+)
+
+// StatusType describes how a file's local state compares to the last known snapshot.
+type StatusType string
+
+const (
+	StatusCreated  StatusType = "created"
+	StatusUpdated  StatusType = "updated"
+	StatusDeleted  StatusType = "deleted"
+	StatusConflict StatusType = "conflict"
+)
+
+// FileMetadata describes a single file as of a directory snapshot.
+type FileMetadata struct {
+	Path    string     `json:"path"`
+	Hash    string     `json:"hash"`
+	Size    int64      `json:"size"`
+	ModTime int64      `json:"modTime"`
+	IsDir   bool       `json:"isDir"`
+	Status  StatusType `json:"status,omitempty"`
+	// Chunks is the ordered list of content-addressed (SHA-256) chunk
+	// hashes that make up the file, used for resumable uploads.
+	Chunks []string `json:"chunks,omitempty"`
+	// LastSyncedHash is the whole-file hash as of the last snapshot both
+	// sides agreed on, used to tell a genuine conflict (both sides changed
+	// it since then) apart from a one-sided change.
+	LastSyncedHash string `json:"lastSyncedHash,omitempty"`
+}
+
+// DirectoryMetadata is a snapshot of an entire sync directory, keyed by
+// normalized (relative, forward-slash) path.
+type DirectoryMetadata struct {
+	Files map[string]FileMetadata `json:"files"`
+}
+
+// FileActionMetadata tells a client what to do with a single file.
+type FileActionMetadata struct {
+	Action ActionType `json:"action"`
+}
+
+// SyncActionMetadata is the set of actions a client needs to take, keyed by
+// normalized path, as decided by the server.
+type SyncActionMetadata struct {
+	Files map[string]FileActionMetadata `json:"files"`
+}